@@ -4,6 +4,7 @@ package gomultifast
 import (
 	"errors"
 	"fmt"
+	"unicode/utf8"
 )
 
 const patternMaxLength = 5000
@@ -11,18 +12,45 @@ const patternStartingLength = 2000
 
 // Automaton contains the Aho-Corasick trie
 type Automaton struct {
-	root          *node   // The root of the Aho-Corasick trie
-	allNodes      []*node // Pointers to all nodes
-	open          bool    // Automaton status. If false, no more patterns can be added
-	currentNode   *node   // Pointer to current node while searching
-	position      int     // The last searched position in a chunk.
-	basePosition  int     // Position of the current chunk related to whole input text
-	totalPatterns int     // Total patterns in the automaton
+	root          *node     // The root of the Aho-Corasick trie
+	dead          *node     // Sentinel node leftmost match kinds fail into to end a run
+	allNodes      []*node   // Pointers to all nodes
+	open          bool      // Automaton status. If false, no more patterns can be added
+	currentNode   *node     // Pointer to current node while searching
+	position      int       // The last searched position in a chunk.
+	basePosition  int       // Position of the current chunk related to whole input text
+	totalPatterns int       // Total patterns in the automaton
+	matchKind     MatchKind // How overlapping candidate matches are resolved during Search
 }
 
 // MatchCallback defines the callback used to handle matches
 type MatchCallback func(Match, string, string) bool
 
+// MatchKind selects how Search resolves candidate matches that overlap or share a start.
+type MatchKind int
+
+const (
+	// Standard reports every match as soon as its final state is reached, including
+	// matches that overlap each other. This is the original gomultifast behavior.
+	Standard MatchKind = iota
+	// LeftmostFirst reports, for each run, the match whose pattern was added to the
+	// automaton first, even if a later-added pattern would match a longer string.
+	LeftmostFirst
+	// LeftmostLongest reports, for each run, the longest match found, regardless of
+	// the order the patterns were added in.
+	LeftmostLongest
+)
+
+// SetMatchKind selects how Search and SearchConcurrent resolve overlapping candidate
+// matches. It must be called before Finalize.
+func (a *Automaton) SetMatchKind(kind MatchKind) error {
+	if !a.open {
+		return errors.New("Error: Closed")
+	}
+	a.matchKind = kind
+	return nil
+}
+
 // Add a Pattern (search term and identifier) to an Automaton
 func (a *Automaton) Add(pattern *pattern) (int, error) {
 	if !a.open {
@@ -60,6 +88,7 @@ func (a *Automaton) Add(pattern *pattern) (int, error) {
 		return -1, errors.New("Error: Duplicate pattern")
 	}
 	n.final = true
+	pattern.order = a.totalPatterns
 	n.registerPattern(pattern)
 	a.totalPatterns++
 	return 0, nil
@@ -79,6 +108,8 @@ func (a *Automaton) registerNode(node *node) {
 func NewAutomaton() *Automaton {
 	a := Automaton{}
 	a.root = nodeCreate()
+	a.dead = nodeCreate()
+	a.dead.failureNode = a.root
 
 	a.registerNode(a.root)
 	a.reset()
@@ -120,6 +151,12 @@ func (a *Automaton) setFailure(node *node, alphas []rune) {
 	if node.failureNode == nil {
 		node.failureNode = a.root
 	}
+	if a.matchKind != Standard && node.final {
+		// A leftmost match must not be preempted by falling back past it to a
+		// shorter, overlapping alternative: cut the fail link so the run ends here
+		// unless it can still be extended via a goto transition.
+		node.failureNode = a.dead
+	}
 }
 
 /*
@@ -197,20 +234,28 @@ func (a *Automaton) Search(text string, keepSearching bool, callback MatchCallba
 		a.reset()
 	}
 
+	if a.matchKind != Standard {
+		stopped, endNode, endPosition, err := a.searchLeftmost(text, 0, a.currentNode, a.basePosition, callback, param)
+		a.currentNode = endNode
+		a.basePosition += endPosition
+		return stopped, err
+	}
+
 	current = a.currentNode
 
-	// Search for the string in text, character by character
+	// Search for the string in text, rune by rune
 	for position < len(text) {
-		next = current.binarySearchNext(rune(text[position]))
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next = current.binarySearchNext(alpha)
 		if next == nil {
 			if current.failureNode != nil {
 				current = current.failureNode
 			} else {
-				position++
+				position += width
 			}
 		} else {
 			current = next
-			position++
+			position += width
 		}
 
 		if current.final && next != nil {
@@ -253,20 +298,26 @@ func (a *Automaton) SearchConcurrent(text string, position int, callback MatchCa
 		return false, errors.New("Automaton not ready. Must be Finalized.")
 	}
 
+	if a.matchKind != Standard {
+		stopped, _, _, err := a.searchLeftmost(text, position, a.root, basePosition, callback, param)
+		return stopped, err
+	}
+
 	current = a.root
 
-	// Search for the string in text, character by character
+	// Search for the string in text, rune by rune
 	for position < len(text) {
-		next = current.binarySearchNext(rune(text[position]))
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next = current.binarySearchNext(alpha)
 		if next == nil {
 			if current.failureNode != nil {
 				current = current.failureNode
 			} else {
-				position++
+				position += width
 			}
 		} else {
 			current = next
-			position++
+			position += width
 		}
 
 		if current.final && next != nil {
@@ -289,6 +340,122 @@ func (a *Automaton) SearchConcurrent(text string, position int, callback MatchCa
 	return false, nil
 }
 
+/*
+searchLeftmost implements Search and SearchConcurrent for the LeftmostFirst and
+LeftmostLongest match kinds. Unlike Standard, a match is not reported the instant
+a final state is reached: the automaton keeps extending the current run and only
+reports the best candidate seen once the run can no longer be extended, either
+because there is no goto and no non-dead fail, or because the input ends.
+*/
+func (a *Automaton) searchLeftmost(text string, position int, start *node, basePosition int, callback MatchCallback, param string) (bool, *node, int, error) {
+	current := start
+	var lastMatch *node
+	lastMatchPos := 0
+
+	report := func() bool {
+		if lastMatch == nil {
+			return false
+		}
+		match := Match{
+			Patterns: []pattern{a.selectMatch(lastMatch.matchedPatterns)},
+			position: lastMatchPos + basePosition,
+		}
+		lastMatch = nil
+		return callback(match, text, param)
+	}
+
+	consider := func(n *node) {
+		if !n.final {
+			return
+		}
+		switch {
+		case lastMatch == nil:
+		case a.matchKind == LeftmostLongest && n.depth <= lastMatch.depth:
+			return
+		case a.matchKind == LeftmostFirst && a.patternOrder(n) >= a.patternOrder(lastMatch):
+			return
+		}
+		lastMatch = n
+		lastMatchPos = position
+	}
+
+	for position < len(text) {
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next := current.binarySearchNext(alpha)
+		if next != nil {
+			current = next
+			position += width
+			consider(current)
+			continue
+		}
+
+		if current.failureNode == nil {
+			if report() {
+				return true, current, position, nil
+			}
+			position += width
+			continue
+		}
+		if current.failureNode == a.dead {
+			if report() {
+				return true, a.root, position, nil
+			}
+			current = a.root
+			continue
+		}
+
+		current = current.failureNode
+		consider(current)
+	}
+
+	if report() {
+		return true, current, position, nil
+	}
+	return false, current, position, nil
+}
+
+// selectMatch picks the single pattern to report for a final state in leftmost
+// mode, applying the configured preference when a state carries more than one
+// candidate (e.g. because a shorter pattern's match was propagated via a failure
+// link before its node became final).
+func (a *Automaton) selectMatch(patterns []pattern) pattern {
+	return pickPattern(patterns, a.matchKind)
+}
+
+// patternOrder returns the smallest insertion order among a node's matched
+// patterns, used to compare candidates under MatchKind LeftmostFirst.
+func (a *Automaton) patternOrder(n *node) int {
+	order := n.matchedPatterns[0].order
+	for _, p := range n.matchedPatterns[1:] {
+		if p.order < order {
+			order = p.order
+		}
+	}
+	return order
+}
+
+// pickPattern picks the single best pattern among patterns for the given
+// MatchKind: the one added to the automaton first for LeftmostFirst, or the
+// longest one for LeftmostLongest. Passing Standard simply returns the first
+// pattern and is only meaningful when patterns has a single element, since
+// Standard mode normally reports every pattern in patterns together.
+func pickPattern(patterns []pattern, kind MatchKind) pattern {
+	best := patterns[0]
+	for _, p := range patterns[1:] {
+		switch kind {
+		case LeftmostFirst:
+			if p.order < best.order {
+				best = p
+			}
+		case LeftmostLongest:
+			if len(p.Pstring) > len(best.Pstring) {
+				best = p
+			}
+		}
+	}
+	return best
+}
+
 // Print out the automaton for debugging purposes.
 func (a *Automaton) Print() {
 	var sid pattern
@@ -0,0 +1,130 @@
+package gomultifast
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSearchDecodesUTF8Runes checks that Search walks text rune by rune
+// rather than byte by byte, so multi-byte patterns and text around them are
+// matched at the correct byte offsets. ½ is the case from the upstream
+// ACAutomaton bug report: a single rune cuts across two bytes that, read as
+// bogus single-byte "runes", don't resemble the pattern at all.
+func TestSearchDecodesUTF8Runes(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		text    string
+		want    int // byte offset where the match ends
+	}{
+		{"ascii", "cat", "a cat sat", 5},
+		{"half_upstream_bug", "½", "price: ½ off", 9},
+		{"multibyte_pattern_in_ascii_text", "café", "visit the café today", 15},
+		{"mixed_ascii_and_multibyte", "x½y", "ax½yb", 5},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAutomaton()
+			if _, err := a.Add(NewPattern(tc.pattern, tc.pattern)); err != nil {
+				t.Fatalf("Add(%q): %v", tc.pattern, err)
+			}
+			a.Finalize()
+
+			var matches []Match
+			if _, err := a.Search(tc.text, false, func(m Match, _ string, _ string) bool {
+				matches = append(matches, m)
+				return false
+			}, ""); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			if len(matches) != 1 {
+				t.Fatalf("Search(%q) in %q = %d matches, want 1", tc.pattern, tc.text, len(matches))
+			}
+			if got := matches[0].Position(); got != tc.want {
+				t.Fatalf("Position() = %d, want %d", got, tc.want)
+			}
+			if got := matches[0].StartOffset(0); got != tc.want-len(tc.pattern) {
+				t.Fatalf("StartOffset(0) = %d, want %d", got, tc.want-len(tc.pattern))
+			}
+		})
+	}
+}
+
+// TestSearchConcurrentDecodesUTF8Runes checks the same rune-correctness for
+// SearchConcurrent, which walks an independent copy of the decoding loop.
+func TestSearchConcurrentDecodesUTF8Runes(t *testing.T) {
+	a := NewAutomaton()
+	if _, err := a.Add(NewPattern("½", "½")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	a.Finalize()
+
+	text := "½ price, ½ off"
+	var matches []Match
+	if _, err := a.SearchConcurrent(text, 0, func(m Match, _ string, _ string) bool {
+		matches = append(matches, m)
+		return false
+	}, ""); err != nil {
+		t.Fatalf("SearchConcurrent: %v", err)
+	}
+
+	if len(matches) != 2 {
+		t.Fatalf("SearchConcurrent(½) in %q = %d matches, want 2", text, len(matches))
+	}
+	if matches[0].Position() != 2 || matches[1].Position() != 12 {
+		t.Fatalf("matches = %+v, want positions 2 and 12", matches)
+	}
+}
+
+// TestSearchMatchKindSamwise checks that LeftmostFirst and LeftmostLongest
+// pick the right candidate when "Sam" and "Samwise" both match the input
+// "Samwise", depending on which order the patterns were added in, while
+// Standard keeps reporting both as separate, overlapping matches.
+func TestSearchMatchKindSamwise(t *testing.T) {
+	const text = "Samwise"
+
+	cases := []struct {
+		name     string
+		patterns []string
+		kind     MatchKind
+		want     []string // Ident of each reported pattern, in report order
+	}{
+		{"standard_sam_first", []string{"Sam", "Samwise"}, Standard, []string{"Sam", "Samwise"}},
+		{"standard_samwise_first", []string{"Samwise", "Sam"}, Standard, []string{"Sam", "Samwise"}},
+		{"leftmost_first_sam_added_first", []string{"Sam", "Samwise"}, LeftmostFirst, []string{"Sam"}},
+		{"leftmost_first_samwise_added_first", []string{"Samwise", "Sam"}, LeftmostFirst, []string{"Samwise"}},
+		{"leftmost_longest_sam_added_first", []string{"Sam", "Samwise"}, LeftmostLongest, []string{"Samwise"}},
+		{"leftmost_longest_samwise_added_first", []string{"Samwise", "Sam"}, LeftmostLongest, []string{"Samwise"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAutomaton()
+			if err := a.SetMatchKind(tc.kind); err != nil {
+				t.Fatalf("SetMatchKind: %v", err)
+			}
+			for _, p := range tc.patterns {
+				if _, err := a.Add(NewPattern(p, p)); err != nil {
+					t.Fatalf("Add(%q): %v", p, err)
+				}
+			}
+			a.Finalize()
+
+			var got []string
+			if _, err := a.Search(text, false, func(m Match, _ string, _ string) bool {
+				for _, p := range m.Patterns {
+					got = append(got, p.Ident)
+				}
+				return false
+			}, ""); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Search(%q) with patterns %v under kind %v reported %v, want %v", text, tc.patterns, tc.kind, got, tc.want)
+			}
+		})
+	}
+}
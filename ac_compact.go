@@ -0,0 +1,353 @@
+package gomultifast
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// deadState is the fail-state id recorded for a node whose fail link was severed
+// by setFailure because the node is itself final under a leftmost MatchKind. It
+// mirrors Automaton's dead sentinel node, see setFailure.
+const deadState int32 = -2
+
+// noFail marks a state with no fail link (only the root has this).
+const noFail int32 = -1
+
+/*
+CompactAutomaton is a flattened form of a finalized Automaton built with the
+double-array trie technique: every state's outgoing edges are stored as a slice
+offset (base) into two parallel int32 arrays (base/check) instead of a
+heap-allocated node with its own []edge slice. A transition from state s on a
+rune with dense code c is the single array lookup base[s]+c, confirmed in O(1)
+by checking check[base[s]+c] == s, with no pointer chasing. This trades the
+Automaton's O(n) memory-per-node pointer trie for a representation with far
+better cache locality on large pattern sets.
+*/
+type CompactAutomaton struct {
+	codes        map[rune]int32 // Maps a rune present in the trie to a dense code
+	base         []int32        // base[s]+code(alpha) is the candidate transition target for state s
+	check        []int32        // check[t] == s confirms the transition s set aside slot t
+	fail         []int32        // Failure state per state id; noFail for the root, deadState if severed
+	final        []bool         // Whether a state is the endpoint of a search
+	depth        []int32        // Rune depth per state id, mirrors Automaton's node.depth
+	output       [][]pattern    // Matched patterns per state, already collected across fail links
+	matchKind    MatchKind      // Mirrors the MatchKind the source Automaton was compiled with
+	minFreeBase  int32          // Lowest check index not yet known to be occupied, advances monotonically
+	currentNode  int32          // State id while searching
+	basePosition int            // Position of the current chunk related to whole input text
+}
+
+// Compile builds a CompactAutomaton from a finalized Automaton. The Automaton
+// must have already had Finalize called on it.
+func (a *Automaton) Compile() (*CompactAutomaton, error) {
+	if a.open {
+		return nil, errors.New("Error: Automaton not Finalized")
+	}
+
+	ca := &CompactAutomaton{
+		codes:     make(map[rune]int32),
+		matchKind: a.matchKind,
+	}
+	for _, n := range a.allNodes {
+		for _, e := range n.outgoing {
+			if _, ok := ca.codes[e.alpha]; !ok {
+				ca.codes[e.alpha] = int32(len(ca.codes))
+			}
+		}
+	}
+
+	ids := make(map[*node]int32, len(a.allNodes))
+	ids[a.root] = 0
+	ca.growTo(1)
+	ca.final[0] = a.root.final
+	ca.depth[0] = int32(a.root.depth)
+	ca.output[0] = append([]pattern(nil), a.root.matchedPatterns...)
+
+	// Place every node's children into the base/check arrays, breadth-first so a
+	// node's compact id is assigned before its own children are placed.
+	queue := []*node{a.root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if len(n.outgoing) == 0 {
+			continue
+		}
+		sid := ids[n]
+		base := ca.findBase(n.outgoing)
+		ca.base[sid] = base
+		for _, e := range n.outgoing {
+			t := base + ca.codes[e.alpha]
+			ca.growTo(int(t) + 1)
+			ca.check[t] = sid
+			ids[e.next] = t
+			ca.final[t] = e.next.final
+			ca.depth[t] = int32(e.next.depth)
+			ca.output[t] = append([]pattern(nil), e.next.matchedPatterns...)
+			queue = append(queue, e.next)
+		}
+	}
+
+	for _, n := range a.allNodes {
+		sid := ids[n]
+		switch {
+		case n.failureNode == nil:
+			ca.fail[sid] = noFail
+		case n.failureNode == a.dead:
+			ca.fail[sid] = deadState
+		default:
+			ca.fail[sid] = ids[n.failureNode]
+		}
+	}
+
+	return ca, nil
+}
+
+// growTo extends the parallel base/check/fail/final/output arrays so they all
+// have at least n entries, keeping their indices in lock-step.
+func (ca *CompactAutomaton) growTo(n int) {
+	for len(ca.check) < n {
+		ca.base = append(ca.base, 0)
+		ca.check = append(ca.check, noFail)
+		ca.fail = append(ca.fail, noFail)
+		ca.final = append(ca.final, false)
+		ca.depth = append(ca.depth, 0)
+		ca.output = append(ca.output, nil)
+	}
+}
+
+// findBase scans candidate base offsets, starting from the lowest slot not yet
+// known to be occupied, until it finds one where every child in edges lands on
+// a free check slot (the classic double-array trie construction).
+func (ca *CompactAutomaton) findBase(edges []edge) int32 {
+	for ca.minFreeBase < int32(len(ca.check)) && ca.check[ca.minFreeBase] != noFail {
+		ca.minFreeBase++
+	}
+
+	for base := ca.minFreeBase; ; base++ {
+		fits := true
+		for _, e := range edges {
+			t := base + ca.codes[e.alpha]
+			// Slot 0 is permanently reserved for the root's own id.
+			if t == 0 || (int(t) < len(ca.check) && ca.check[t] != noFail) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return base
+		}
+	}
+}
+
+// transition returns the state reached from s on alpha via a goto edge, and
+// whether such an edge exists.
+func (ca *CompactAutomaton) transition(s int32, alpha rune) (int32, bool) {
+	code, ok := ca.codes[alpha]
+	if !ok {
+		return 0, false
+	}
+	t := ca.base[s] + code
+	if t < 0 || int(t) >= len(ca.check) || ca.check[t] != s {
+		return 0, false
+	}
+	return t, true
+}
+
+// reset makes the next stateful Search begin from the root state.
+func (ca *CompactAutomaton) reset() {
+	ca.currentNode = 0
+	ca.basePosition = 0
+}
+
+/*
+Search mirrors Automaton.Search over the compact representation: it walks
+"text" rune by rune and, on a match, calls callback with the searched text and
+the string parameter in "param". If keepSearching is true, the search resumes
+from the state left by the previous call instead of the root.
+*/
+func (ca *CompactAutomaton) Search(text string, keepSearching bool, callback MatchCallback, param string) (bool, error) {
+	if !keepSearching {
+		ca.reset()
+	}
+
+	if ca.matchKind != Standard {
+		stopped, endState, endPosition := ca.searchLeftmost(text, 0, ca.currentNode, ca.basePosition, callback, param)
+		ca.currentNode = endState
+		ca.basePosition += endPosition
+		return stopped, nil
+	}
+
+	position := 0
+	current := ca.currentNode
+	var match Match
+
+	for position < len(text) {
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next, ok := ca.transition(current, alpha)
+		matched := ok
+		if ok {
+			current = next
+			position += width
+		} else if ca.fail[current] >= 0 {
+			current = ca.fail[current]
+		} else {
+			position += width
+		}
+
+		if ca.final[current] && matched {
+			match.position = position + ca.basePosition
+			match.Patterns = ca.output[current]
+			if callback(match, text, param) {
+				return true, nil
+			}
+		}
+		if position >= len(text) {
+			break
+		}
+	}
+
+	ca.currentNode = current
+	ca.basePosition += position
+	return false, nil
+}
+
+/*
+SearchConcurrent mirrors Automaton.SearchConcurrent: it searches "text" starting
+at "position" without touching any saved state, so it is safe to call
+concurrently from multiple goroutines against the same CompactAutomaton.
+*/
+func (ca *CompactAutomaton) SearchConcurrent(text string, position int, callback MatchCallback, param string) (bool, error) {
+	if ca.matchKind != Standard {
+		stopped, _, _ := ca.searchLeftmost(text, position, 0, 0, callback, param)
+		return stopped, nil
+	}
+
+	var current int32
+	var match Match
+
+	for position < len(text) {
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next, ok := ca.transition(current, alpha)
+		matched := ok
+		if ok {
+			current = next
+			position += width
+		} else if ca.fail[current] >= 0 {
+			current = ca.fail[current]
+		} else {
+			position += width
+		}
+
+		if ca.final[current] && matched {
+			match.position = position
+			match.Patterns = ca.output[current]
+			if callback(match, text, param) {
+				return true, nil
+			}
+		}
+		if position >= len(text) {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+// searchLeftmost is the compact-array counterpart of Automaton.searchLeftmost,
+// used when the source Automaton was compiled with a non-Standard MatchKind.
+func (ca *CompactAutomaton) searchLeftmost(text string, position int, start int32, basePosition int, callback MatchCallback, param string) (bool, int32, int) {
+	current := start
+	lastMatch := int32(-1)
+	lastMatchPos := 0
+
+	report := func() bool {
+		if lastMatch < 0 {
+			return false
+		}
+		match := Match{
+			Patterns: []pattern{ca.selectMatch(ca.output[lastMatch])},
+			position: lastMatchPos + basePosition,
+		}
+		lastMatch = -1
+		return callback(match, text, param)
+	}
+
+	consider := func(s int32) {
+		if !ca.final[s] {
+			return
+		}
+		switch {
+		case lastMatch < 0:
+		case ca.matchKind == LeftmostLongest && ca.depth[s] <= ca.depth[lastMatch]:
+			return
+		case ca.matchKind == LeftmostFirst && ca.patternOrder(s) >= ca.patternOrder(lastMatch):
+			return
+		}
+		lastMatch = s
+		lastMatchPos = position
+	}
+
+	for position < len(text) {
+		alpha, width := utf8.DecodeRuneInString(text[position:])
+		next, ok := ca.transition(current, alpha)
+		if ok {
+			current = next
+			position += width
+			consider(current)
+			continue
+		}
+
+		switch ca.fail[current] {
+		case noFail:
+			if report() {
+				return true, current, position
+			}
+			position += width
+		case deadState:
+			if report() {
+				return true, 0, position
+			}
+			current = 0
+		default:
+			current = ca.fail[current]
+			consider(current)
+		}
+	}
+
+	if report() {
+		return true, current, position
+	}
+	return false, current, position
+}
+
+// patternOrder returns the smallest insertion order among a state's matched
+// patterns, used to compare candidates under MatchKind LeftmostFirst.
+func (ca *CompactAutomaton) patternOrder(s int32) int {
+	order := ca.output[s][0].order
+	for _, p := range ca.output[s][1:] {
+		if p.order < order {
+			order = p.order
+		}
+	}
+	return order
+}
+
+// selectMatch picks the single pattern to report for a final state in leftmost
+// mode, applying the configured preference when a state carries more than one
+// candidate.
+func (ca *CompactAutomaton) selectMatch(patterns []pattern) pattern {
+	best := patterns[0]
+	for _, p := range patterns[1:] {
+		switch ca.matchKind {
+		case LeftmostFirst:
+			if p.order < best.order {
+				best = p
+			}
+		case LeftmostLongest:
+			if len(p.Pstring) > len(best.Pstring) {
+				best = p
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,170 @@
+package gomultifast
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// fuzzAlphabet mixes ASCII with multi-byte runes so generated patterns and
+// text exercise the same UTF-8 edge cases chunk0-1 fixed.
+var fuzzAlphabet = []rune{'a', 'b', 'c', 'd', '½', 'é', 'ñ'}
+
+func randomRuneString(r *rand.Rand, maxLen int) string {
+	n := 1 + r.Intn(maxLen)
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = fuzzAlphabet[r.Intn(len(fuzzAlphabet))]
+	}
+	return string(runes)
+}
+
+// buildCompiledPair builds an Automaton for patterns under kind, finalizes it,
+// and compiles it to a CompactAutomaton, returning both so a test can compare
+// search results between the two backends.
+func buildCompiledPair(t *testing.T, patterns []string, kind MatchKind) (*Automaton, *CompactAutomaton) {
+	t.Helper()
+	a := NewAutomaton()
+	if err := a.SetMatchKind(kind); err != nil {
+		t.Fatalf("SetMatchKind: %v", err)
+	}
+	for _, p := range patterns {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			continue // duplicate pattern in this trial, harmless to skip
+		}
+	}
+	a.Finalize()
+
+	ca, err := a.Compile()
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return a, ca
+}
+
+func matchedIdents(search func(MatchCallback) (bool, error)) ([]string, error) {
+	var idents []string
+	_, err := search(func(m Match, _ string, _ string) bool {
+		for _, p := range m.Patterns {
+			idents = append(idents, p.Ident)
+		}
+		return false
+	})
+	return idents, err
+}
+
+// TestCompactAutomatonMatchesAutomaton randomly generates pattern sets and
+// text over an alphabet that includes multi-byte runes, and checks that
+// CompactAutomaton reports exactly the same matches as the Automaton it was
+// compiled from, under every MatchKind.
+func TestCompactAutomatonMatchesAutomaton(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for trial := 0; trial < 300; trial++ {
+		numPatterns := 2 + r.Intn(4)
+		patterns := make([]string, numPatterns)
+		for i := range patterns {
+			patterns[i] = randomRuneString(r, 5)
+		}
+		text := randomRuneString(r, 20)
+
+		for _, kind := range []MatchKind{Standard, LeftmostFirst, LeftmostLongest} {
+			a, ca := buildCompiledPair(t, patterns, kind)
+
+			want, err := matchedIdents(func(cb MatchCallback) (bool, error) {
+				return a.Search(text, false, cb, "")
+			})
+			if err != nil {
+				t.Fatalf("trial %d kind %v: Automaton.Search: %v", trial, kind, err)
+			}
+
+			got, err := matchedIdents(func(cb MatchCallback) (bool, error) {
+				return ca.Search(text, false, cb, "")
+			})
+			if err != nil {
+				t.Fatalf("trial %d kind %v: CompactAutomaton.Search: %v", trial, kind, err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("trial %d kind %v patterns %v text %q: Automaton=%v CompactAutomaton=%v",
+					trial, kind, patterns, text, want, got)
+			}
+		}
+	}
+}
+
+// TestCompactAutomatonLeftmostLongestUsesStateDepth is a regression test for a
+// specific disagreement a 2000-trial fuzz run found: patterns
+// ["½bcé","bdcñ","c","ñ","cdb"] under LeftmostLongest against
+// "écdñédañ½ñ½aa" picked a shorter match than the pointer Automaton, because
+// the compact backend compared candidates' byte lengths by peeking at a
+// state's first propagated pattern instead of the state's own rune depth.
+func TestCompactAutomatonLeftmostLongestUsesStateDepth(t *testing.T) {
+	patterns := []string{"½bcé", "bdcñ", "c", "ñ", "cdb"}
+	text := "écdñédañ½ñ½aa"
+
+	a, ca := buildCompiledPair(t, patterns, LeftmostLongest)
+
+	want, err := matchedIdents(func(cb MatchCallback) (bool, error) {
+		return a.Search(text, false, cb, "")
+	})
+	if err != nil {
+		t.Fatalf("Automaton.Search: %v", err)
+	}
+
+	got, err := matchedIdents(func(cb MatchCallback) (bool, error) {
+		return ca.Search(text, false, cb, "")
+	})
+	if err != nil {
+		t.Fatalf("CompactAutomaton.Search: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Automaton=%v CompactAutomaton=%v, want equal", want, got)
+	}
+}
+
+func buildBenchPair(b *testing.B, numPatterns, textLen int) (*Automaton, *CompactAutomaton, string) {
+	b.Helper()
+	r := rand.New(rand.NewSource(42))
+
+	a := NewAutomaton()
+	for i := 0; i < numPatterns; i++ {
+		p := randomRuneString(r, 8)
+		a.Add(NewPattern(p, p))
+	}
+	a.Finalize()
+
+	ca, err := a.Compile()
+	if err != nil {
+		b.Fatalf("Compile: %v", err)
+	}
+
+	return a, ca, randomRuneString(r, textLen)
+}
+
+// BenchmarkAutomatonSearch and BenchmarkCompactAutomatonSearch compare the
+// pointer-trie and double-array backends over the same pattern set and text.
+func BenchmarkAutomatonSearch(b *testing.B) {
+	a, _, text := buildBenchPair(b, 2000, 20000)
+	noop := func(Match, string, string) bool { return false }
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.Search(text, false, noop, ""); err != nil {
+			b.Fatalf("Search: %v", err)
+		}
+	}
+}
+
+func BenchmarkCompactAutomatonSearch(b *testing.B) {
+	_, ca, text := buildBenchPair(b, 2000, 20000)
+	noop := func(Match, string, string) bool { return false }
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ca.Search(text, false, noop, ""); err != nil {
+			b.Fatalf("Search: %v", err)
+		}
+	}
+}
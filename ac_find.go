@@ -0,0 +1,176 @@
+package gomultifast
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// SearchOptions configures FindAll and Iter.
+type SearchOptions struct {
+	// Anchored restricts reported matches to those starting at Start: the
+	// search stops the moment it would have to fall back through a failure
+	// link (or, at root, skip a rune with no outgoing edge), since that can
+	// only ever find a match starting later than Start.
+	Anchored bool
+	// Start and End restrict the search to text[Start:End]. End of zero
+	// means len(text).
+	Start, End int
+	// Overlapping controls whether a match can start inside one already
+	// reported. When false, only the longest pattern at a final state is
+	// reported and the search resumes from the root at that position,
+	// skipping any shorter alternative that starts inside it.
+	Overlapping bool
+	// MatchKind selects which pattern to report when a final state carries
+	// more than one candidate and Overlapping suppression doesn't already
+	// decide it. Standard reports every candidate together, as one Match.
+	MatchKind MatchKind
+}
+
+// FindAll returns every match of a Finalized automaton against text honoring opts.
+func (a *Automaton) FindAll(text string, opts SearchOptions) ([]Match, error) {
+	it, err := a.Iter(text, opts)
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for {
+		m, ok := it.Next()
+		if !ok {
+			break
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// MatchIter lazily walks the matches FindAll would return, without
+// materializing the full list up front.
+type MatchIter struct {
+	a        *Automaton
+	text     string
+	opts     SearchOptions
+	current  *node
+	position int
+	end      int
+	queue    []Match
+	done     bool
+}
+
+// Iter returns a lazy iterator over the matches FindAll would find for text
+// under opts. a must be Finalized.
+func (a *Automaton) Iter(text string, opts SearchOptions) (*MatchIter, error) {
+	if a.open {
+		return nil, errors.New("Automaton not ready. Must be Finalized.")
+	}
+	end := opts.End
+	if end == 0 {
+		end = len(text)
+	}
+	return &MatchIter{
+		a:        a,
+		text:     text,
+		opts:     opts,
+		current:  a.root,
+		position: opts.Start,
+		end:      end,
+	}, nil
+}
+
+// Next returns the next match, or false once the search is exhausted.
+func (it *MatchIter) Next() (Match, bool) {
+	if len(it.queue) > 0 {
+		m := it.queue[0]
+		it.queue = it.queue[1:]
+		return m, true
+	}
+	if it.done {
+		return Match{}, false
+	}
+
+	for it.position < it.end {
+		alpha, width := utf8.DecodeRuneInString(it.text[it.position:])
+		next := it.current.binarySearchNext(alpha)
+		if next == nil {
+			if it.opts.Anchored {
+				// Any failure-link traversal (or, at root, skipping this
+				// rune) necessarily picks up a suffix of the run that began
+				// at Start, so whatever it finds next starts later than
+				// Start. That holds whether or not a match was already
+				// reported for this run, so stop here unconditionally.
+				it.done = true
+				return Match{}, false
+			}
+			if it.current.failureNode == nil {
+				it.position += width
+				continue
+			}
+			it.current = it.current.failureNode
+			continue
+		}
+
+		it.current = next
+		it.position += width
+
+		if !it.current.final {
+			continue
+		}
+		it.emit(it.position)
+		if len(it.queue) > 0 {
+			m := it.queue[0]
+			it.queue = it.queue[1:]
+			return m, true
+		}
+	}
+
+	it.done = true
+	return Match{}, false
+}
+
+// emit queues the match(es) to report for a final state reached at position,
+// applying Overlapping and MatchKind.
+func (it *MatchIter) emit(position int) {
+	patterns := it.current.matchedPatterns
+	if it.opts.Anchored {
+		// matchedPatterns was populated at Finalize time by
+		// collectAllMatchedPatterns, which copies in every pattern reachable
+		// via a failure link regardless of where the search-time walk came
+		// from. Those copied-in patterns are suffixes of the current run and
+		// so start later than it did; keep only the ones that actually start
+		// at Start.
+		patterns = anchoredPatterns(patterns, it.opts.Start, position)
+		if len(patterns) == 0 {
+			return
+		}
+	}
+	if !it.opts.Overlapping {
+		longest := pickPattern(patterns, LeftmostLongest)
+		it.queue = append(it.queue, Match{Patterns: []pattern{longest}, position: position})
+		// Jumping back to root to skip overlapping matches starts a new,
+		// unanchored run: Anchored only promises matches starting at Start,
+		// so the iterator must stop here rather than keep scanning from root.
+		if it.opts.Anchored {
+			it.done = true
+			return
+		}
+		it.current = it.a.root
+		return
+	}
+	if it.opts.MatchKind == Standard {
+		it.queue = append(it.queue, Match{Patterns: patterns, position: position})
+		return
+	}
+	it.queue = append(it.queue, Match{Patterns: []pattern{pickPattern(patterns, it.opts.MatchKind)}, position: position})
+}
+
+// anchoredPatterns filters patterns down to those whose implied start offset
+// (position - len(Pstring)) equals start, discarding any pattern that a
+// failure-link propagation copied in from a different, later-starting run.
+func anchoredPatterns(patterns []pattern, start, position int) []pattern {
+	var kept []pattern
+	for _, p := range patterns {
+		if position-len(p.Pstring) == start {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
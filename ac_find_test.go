@@ -0,0 +1,93 @@
+package gomultifast
+
+import "testing"
+
+// TestFindAllAnchoredStopsAtStart checks that Anchored doesn't report matches
+// starting after Start once overlap suppression resets the search to root:
+// that reset begins a fresh, unanchored scan, which Anchored must not surface.
+func TestFindAllAnchoredStopsAtStart(t *testing.T) {
+	a := NewAutomaton()
+	for _, p := range []string{"ab", "cd"} {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	a.Finalize()
+
+	matches, err := a.FindAll("abcd", SearchOptions{Anchored: true, Start: 0})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Position() != 2 {
+		t.Fatalf("FindAll(Anchored) = %+v, want a single match ending at 2", matches)
+	}
+}
+
+// TestFindAllAnchoredStopsAfterFailureLink checks that Anchored doesn't report
+// a later-starting match reached by falling back through a failure link, even
+// after a first, correctly-anchored match was already reported. Overlapping is
+// true here precisely so the search keeps running past the first match instead
+// of resetting to root, to exercise the failure-link path rather than the
+// overlap-suppression reset covered by TestFindAllAnchoredStopsAtStart.
+func TestFindAllAnchoredStopsAfterFailureLink(t *testing.T) {
+	a := NewAutomaton()
+	for _, p := range []string{"ab", "bc"} {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	a.Finalize()
+
+	matches, err := a.FindAll("abc", SearchOptions{Anchored: true, Overlapping: true, Start: 0})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Position() != 2 {
+		t.Fatalf("FindAll(Anchored) = %+v, want a single match ending at 2 (\"ab\"), not \"bc\"", matches)
+	}
+}
+
+// TestFindAllAnchoredFiltersPropagatedSuffixPatterns checks that Anchored
+// drops a shorter pattern that is a suffix of a longer one reported at the
+// same final state: "b" is a proper suffix of "ab", so Finalize's
+// collectAllMatchedPatterns copies it into "ab"'s node via the failure link,
+// even though "b" itself starts at byte 1, not Start.
+func TestFindAllAnchoredFiltersPropagatedSuffixPatterns(t *testing.T) {
+	a := NewAutomaton()
+	for _, p := range []string{"b", "ab"} {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	a.Finalize()
+
+	matches, err := a.FindAll("ab", SearchOptions{Anchored: true, Overlapping: true})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(matches) != 1 || len(matches[0].Patterns) != 1 || matches[0].Patterns[0].Ident != "ab" {
+		t.Fatalf("FindAll(Anchored, Overlapping) = %+v, want a single match for \"ab\" only", matches)
+	}
+}
+
+// TestFindAllAnchoredLeftmostFirstFiltersPropagatedSuffixPatterns checks the
+// same filtering under MatchKind LeftmostFirst, where "b" (inserted first)
+// would otherwise win pickPattern's tie-break over the anchored "ab" even
+// though "b" doesn't start at Start.
+func TestFindAllAnchoredLeftmostFirstFiltersPropagatedSuffixPatterns(t *testing.T) {
+	a := NewAutomaton()
+	for _, p := range []string{"b", "ab"} {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	a.Finalize()
+
+	matches, err := a.FindAll("ab", SearchOptions{Anchored: true, MatchKind: LeftmostFirst})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(matches) != 1 || len(matches[0].Patterns) != 1 || matches[0].Patterns[0].Ident != "ab" {
+		t.Fatalf("FindAll(Anchored, LeftmostFirst) = %+v, want a single match for \"ab\" only", matches)
+	}
+}
@@ -0,0 +1,316 @@
+package gomultifast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// marshalMagic identifies a gomultifast automaton binary. marshalVersion is
+// bumped whenever the layout below changes incompatibly.
+var marshalMagic = [4]byte{'G', 'M', 'F', 'A'}
+
+const marshalVersion uint32 = 1
+
+// Sentinel failure-node indices used in the serialized format in place of a
+// node ID: the root has no failure node, and the dead state used by
+// non-Standard MatchKinds is never part of allNodes.
+const (
+	noFailureNode   int32 = -1
+	deadFailureNode int32 = -2
+)
+
+/*
+MarshalBinary serializes a Finalized automaton to a versioned, little-endian
+binary format so it can be persisted and reloaded without rebuilding the trie.
+
+The format stores a deduplicated pattern table (Ident and Pstring as
+length-prefixed UTF-8; today's in-memory matchedPatterns instead duplicate a
+pattern struct into every node it propagates to) followed by the node array in
+id order, each with its depth, final flag, failure-node index, sorted outgoing
+edges as (rune, nodeID) pairs, and matchedPatterns as indices into the pattern
+table.
+*/
+func (a *Automaton) MarshalBinary() ([]byte, error) {
+	if a.open {
+		return nil, errors.New("Error: Automaton not ready. Must be Finalized.")
+	}
+
+	patternTable := make([]pattern, a.totalPatterns)
+	have := make([]bool, a.totalPatterns)
+	for _, n := range a.allNodes {
+		for _, p := range n.matchedPatterns {
+			if !have[p.order] {
+				patternTable[p.order] = p
+				have[p.order] = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(marshalMagic[:])
+	writeUint32(&buf, marshalVersion)
+	writeUint32(&buf, uint32(a.matchKind))
+
+	writeUint32(&buf, uint32(len(patternTable)))
+	for _, p := range patternTable {
+		writeString(&buf, p.Ident)
+		writeString(&buf, p.Pstring)
+	}
+
+	// allNodes is ordered by creation, but node.id is a counter shared across
+	// every Automaton in the process, so it cannot double as a position in
+	// this automaton's node array: map each node to its array index instead.
+	nodeIndex := make(map[*node]int32, len(a.allNodes))
+	for i, n := range a.allNodes {
+		nodeIndex[n] = int32(i)
+	}
+
+	writeUint32(&buf, uint32(len(a.allNodes)))
+	for _, n := range a.allNodes {
+		writeUint32(&buf, uint32(n.depth))
+		if n.final {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+
+		var failIdx int32
+		switch n.failureNode {
+		case nil:
+			failIdx = noFailureNode
+		case a.dead:
+			failIdx = deadFailureNode
+		default:
+			failIdx = nodeIndex[n.failureNode]
+		}
+		writeInt32(&buf, failIdx)
+
+		writeUint32(&buf, uint32(len(n.outgoing)))
+		for _, e := range n.outgoing {
+			writeInt32(&buf, int32(e.alpha))
+			writeInt32(&buf, nodeIndex[e.next])
+		}
+
+		writeUint32(&buf, uint32(len(n.matchedPatterns)))
+		for _, p := range n.matchedPatterns {
+			writeUint32(&buf, uint32(p.order))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+/*
+UnmarshalAutomaton reconstructs an automaton previously written by
+MarshalBinary. The result is Finalized (open is false): no further patterns
+can be Added, matching the on-disk trie is immutable.
+*/
+func UnmarshalAutomaton(data []byte) (*Automaton, error) {
+	r := &byteReader{data: data}
+
+	magic, err := r.bytes(len(marshalMagic))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, marshalMagic[:]) {
+		return nil, errors.New("Error: not a gomultifast automaton")
+	}
+	version, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if version != marshalVersion {
+		return nil, fmt.Errorf("Error: unsupported automaton format version %d", version)
+	}
+	matchKind, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	patternCount, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	patternTable := make([]pattern, patternCount)
+	for i := range patternTable {
+		ident, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		pstring, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		patternTable[i] = pattern{Ident: ident, Pstring: pstring, order: i}
+	}
+
+	nodeCount, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	type pendingEdge struct {
+		alpha  rune
+		nextID int32
+	}
+
+	nodes := make([]*node, nodeCount)
+	edges := make([][]pendingEdge, nodeCount)
+	failIdx := make([]int32, nodeCount)
+
+	for i := range nodes {
+		depth, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		final, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		fi, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		failIdx[i] = fi
+
+		edgeCount, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		nodeEdges := make([]pendingEdge, edgeCount)
+		for j := range nodeEdges {
+			alpha, err := r.int32()
+			if err != nil {
+				return nil, err
+			}
+			nextID, err := r.int32()
+			if err != nil {
+				return nil, err
+			}
+			nodeEdges[j] = pendingEdge{alpha: rune(alpha), nextID: nextID}
+		}
+		edges[i] = nodeEdges
+
+		patCount, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]pattern, patCount)
+		for j := range matched {
+			idx, err := r.uint32()
+			if err != nil {
+				return nil, err
+			}
+			if idx >= patternCount {
+				return nil, errors.New("Error: pattern index out of range")
+			}
+			matched[j] = patternTable[idx]
+		}
+
+		nodes[i] = &node{id: i, depth: int(depth), final: final != 0, matchedPatterns: matched}
+	}
+
+	dead := &node{id: -1}
+	for i, n := range nodes {
+		switch failIdx[i] {
+		case noFailureNode:
+			n.failureNode = nil
+		case deadFailureNode:
+			n.failureNode = dead
+		default:
+			if failIdx[i] < 0 || int(failIdx[i]) >= len(nodes) {
+				return nil, errors.New("Error: failure node index out of range")
+			}
+			n.failureNode = nodes[failIdx[i]]
+		}
+
+		n.outgoing = make([]edge, len(edges[i]))
+		for j, pe := range edges[i] {
+			if pe.nextID < 0 || int(pe.nextID) >= len(nodes) {
+				return nil, errors.New("Error: edge target out of range")
+			}
+			n.outgoing[j] = edge{alpha: pe.alpha, next: nodes[pe.nextID]}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("Error: automaton has no root node")
+	}
+	dead.failureNode = nodes[0]
+
+	a := &Automaton{
+		root:          nodes[0],
+		dead:          dead,
+		allNodes:      nodes,
+		totalPatterns: int(patternCount),
+		matchKind:     MatchKind(matchKind),
+	}
+	a.reset()
+	return a, nil
+}
+
+// byteReader is a bounds-checked cursor over a serialized automaton, since
+// the input may be a corrupt or truncated file rather than trusted data we
+// produced ourselves.
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errors.New("Error: truncated automaton data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *byteReader) byte() (byte, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *byteReader) uint32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *byteReader) int32() (int32, error) {
+	v, err := r.uint32()
+	return int32(v), err
+}
+
+func (r *byteReader) string() (string, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeInt32(buf *bytes.Buffer, v int32) {
+	writeUint32(buf, uint32(v))
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUint32(buf, uint32(len(s)))
+	buf.WriteString(s)
+}
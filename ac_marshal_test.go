@@ -0,0 +1,80 @@
+package gomultifast
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+const goldenAutomatonPath = "testdata/automaton.golden"
+
+func buildGoldenAutomaton() *Automaton {
+	a := NewAutomaton()
+	for _, p := range []string{"he", "she", "his", "hers"} {
+		a.Add(NewPattern(p, p))
+	}
+	a.Finalize()
+	return a
+}
+
+func searchAll(t *testing.T, a *Automaton, text string) []Match {
+	t.Helper()
+	var matches []Match
+	_, err := a.Search(text, false, func(m Match, _ string, _ string) bool {
+		matches = append(matches, m)
+		return false
+	}, "")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	return matches
+}
+
+// TestUnmarshalAutomatonGoldenParity loads the checked-in golden file (built
+// by the same pattern set as buildGoldenAutomaton) and verifies it matches
+// text exactly like a freshly built, never-serialized automaton.
+func TestUnmarshalAutomatonGoldenParity(t *testing.T) {
+	golden, err := os.ReadFile(goldenAutomatonPath)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+
+	loaded, err := UnmarshalAutomaton(golden)
+	if err != nil {
+		t.Fatalf("UnmarshalAutomaton: %v", err)
+	}
+
+	fresh := buildGoldenAutomaton()
+
+	const text = "ushers"
+	wantMatches := searchAll(t, fresh, text)
+	gotMatches := searchAll(t, loaded, text)
+
+	if !reflect.DeepEqual(gotMatches, wantMatches) {
+		t.Fatalf("loaded automaton matches = %+v, want %+v", gotMatches, wantMatches)
+	}
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	a := buildGoldenAutomaton()
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	loaded, err := UnmarshalAutomaton(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAutomaton: %v", err)
+	}
+
+	const text = "ushers"
+	want := searchAll(t, a, text)
+	got := searchAll(t, loaded, text)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round-tripped automaton matches = %+v, want %+v", got, want)
+	}
+
+	if _, err := loaded.Add(NewPattern("x", "x")); err == nil {
+		t.Fatal("Add on an unmarshaled automaton should fail: it must stay closed")
+	}
+}
@@ -15,6 +15,7 @@ type edge struct {
 type pattern struct {
 	Pstring string // String to add to trie
 	Ident   string // String identifier
+	order   int    // Insertion order, used to break ties under MatchKind LeftmostFirst
 }
 
 // A node in the trie structure
@@ -30,7 +31,18 @@ type node struct {
 // Match contains all found matches with some details
 type Match struct {
 	Patterns []pattern // Slice containing matched patterns in the text
-	position int       // The end position of matching patterns in the text
+	position int       // The end position (in bytes) of matching patterns in the text
+}
+
+// Position returns the byte offset in the searched text where the match ended.
+func (m Match) Position() int {
+	return m.position
+}
+
+// StartOffset returns the byte offset in the searched text where the pattern at
+// patternIndex (an index into m.Patterns) began.
+func (m Match) StartOffset(patternIndex int) int {
+	return m.position - len(m.Patterns[patternIndex].Pstring)
 }
 
 // Alphabetical implements sort.Interface for []Edge based on alphabetical position of Edge.alpha
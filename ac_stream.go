@@ -0,0 +1,134 @@
+package gomultifast
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// defaultStreamBufferSize is the size of the buffer SearchStream reads into
+// when no StreamOption overrides it.
+const defaultStreamBufferSize = 64 * 1024
+
+// streamConfig holds the options SearchStream accepts.
+type streamConfig struct {
+	bufferSize int
+}
+
+// StreamOption configures SearchStream.
+type StreamOption func(*streamConfig)
+
+// WithBufferSize overrides SearchStream's default 64 KiB read buffer.
+func WithBufferSize(n int) StreamOption {
+	return func(c *streamConfig) {
+		c.bufferSize = n
+	}
+}
+
+// StreamMatchCallback is the callback type for SearchStream. m carries
+// absolute byte offsets into the stream, the same as a stateful Search call
+// would report, but window is only a bounded trailing slice of everything
+// searched so far, not the whole stream, so it does not itself start at
+// stream offset 0. windowStart is the absolute stream offset of window's
+// first byte: callers can recover a match's bytes from window with
+// window[m.StartOffset(i)-windowStart : m.Position()-windowStart].
+type StreamMatchCallback func(m Match, window string, windowStart int, param string) bool
+
+/*
+SearchStream searches a Finalized automaton across r without requiring the
+whole input in memory. It reads r in chunks (64 KiB by default, see
+WithBufferSize), preserving the automaton's state (currentNode, basePosition)
+across reads exactly like the stateful Search, and never splits a multi-byte
+UTF-8 rune across a read boundary: an incomplete trailing sequence is buffered
+and prepended to the next read.
+
+Match positions passed to callback are absolute byte offsets into the stream,
+not into the current chunk. The window argument given to callback is a slice
+containing at least the matched pattern's bytes plus up to patternMaxLength
+bytes of trailing context from before it, so callbacks can inspect a match
+even when it straddles a read boundary; windowStart lets callers translate m's
+absolute offsets into window-relative ones for slicing window itself.
+*/
+func (a *Automaton) SearchStream(r io.Reader, callback StreamMatchCallback, param string, opts ...StreamOption) error {
+	if a.open {
+		return errors.New("Automaton not ready. Must be Finalized.")
+	}
+
+	cfg := streamConfig{bufferSize: defaultStreamBufferSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	a.reset()
+
+	buf := make([]byte, cfg.bufferSize)
+	var pending []byte // incomplete trailing UTF-8 sequence carried over from the previous read
+	var context []byte // up to patternMaxLength trailing bytes already searched, kept for callback text
+
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			chunk := append(pending, buf[:n]...)
+			usable, trailer := splitIncompleteTrailer(chunk)
+			// usable aliases chunk's backing array (and so can alias pending's,
+			// if append above didn't need to grow it): reusing pending[:0] here
+			// would overwrite usable's front bytes before Search reads them.
+			pending = append([]byte(nil), trailer...)
+
+			if len(usable) > 0 {
+				window := append(append([]byte(nil), context...), usable...)
+				windowStart := a.basePosition - len(context)
+				wrapped := func(m Match, _ string, param string) bool {
+					return callback(m, string(window), windowStart, param)
+				}
+
+				stop, err := a.Search(string(usable), true, wrapped, param)
+				if err != nil {
+					return err
+				}
+				if stop {
+					return nil
+				}
+
+				context = trailingContext(window)
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// splitIncompleteTrailer splits b into the longest prefix that ends on a
+// complete rune and a trailer holding a possibly-incomplete rune started in
+// the last few bytes of b.
+func splitIncompleteTrailer(b []byte) (usable, trailer []byte) {
+	limit := utf8.UTFMax - 1
+	if limit > len(b) {
+		limit = len(b)
+	}
+	for i := 1; i <= limit; i++ {
+		start := len(b) - i
+		if !utf8.RuneStart(b[start]) {
+			continue
+		}
+		if !utf8.FullRune(b[start:]) {
+			return b[:start], b[start:]
+		}
+		break
+	}
+	return b, nil
+}
+
+// trailingContext returns the last patternMaxLength bytes of b, the most that
+// any single pattern could need for callback context.
+func trailingContext(b []byte) []byte {
+	if len(b) <= patternMaxLength {
+		return append([]byte(nil), b...)
+	}
+	return append([]byte(nil), b[len(b)-patternMaxLength:]...)
+}
@@ -0,0 +1,97 @@
+package gomultifast
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// searchPositions builds a fresh automaton for patterns and returns the
+// Search match end-positions against text, in order.
+func searchPositions(t *testing.T, patterns []string, text string) []int {
+	t.Helper()
+	a := NewAutomaton()
+	for _, p := range patterns {
+		if _, err := a.Add(NewPattern(p, p)); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	a.Finalize()
+
+	var positions []int
+	_, err := a.Search(text, false, func(m Match, _ string, _ string) bool {
+		positions = append(positions, m.Position())
+		return false
+	}, "")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	return positions
+}
+
+// TestSearchStreamMatchesSearchAtEveryBoundary splits the same input at every
+// possible read-buffer size, including sizes that land mid-rune and mid-match,
+// and checks SearchStream reports exactly the same matches as a single-shot
+// Search. This guards against pending/usable aliasing corrupting a read that
+// straddles a buffer boundary.
+func TestSearchStreamMatchesSearchAtEveryBoundary(t *testing.T) {
+	patterns := []string{"ñdd", "ñbñ", "d", "ñaé"}
+	text := "ñdñaaacdaédddbñdñdééd"
+
+	want := searchPositions(t, patterns, text)
+
+	for bufSize := 1; bufSize <= len(text); bufSize++ {
+		a := NewAutomaton()
+		for _, p := range patterns {
+			if _, err := a.Add(NewPattern(p, p)); err != nil {
+				t.Fatalf("Add(%q): %v", p, err)
+			}
+		}
+		a.Finalize()
+
+		var got []int
+		err := a.SearchStream(bytes.NewReader([]byte(text)), func(m Match, _ string, _ int, _ string) bool {
+			got = append(got, m.Position())
+			return false
+		}, "", WithBufferSize(bufSize))
+		if err != nil {
+			t.Fatalf("SearchStream with buffer size %d: %v", bufSize, err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("buffer size %d: SearchStream positions = %v, want %v", bufSize, got, want)
+		}
+	}
+}
+
+// TestSearchStreamWindowSliceableAfterContextTrimming streams past
+// patternMaxLength bytes before the match, so context has been trimmed down
+// from the full prior stream and window no longer starts at stream offset 0.
+// windowStart must let the callback translate m's absolute offsets into
+// window-relative ones without the slice bounds panicking.
+func TestSearchStreamWindowSliceableAfterContextTrimming(t *testing.T) {
+	const pattern = "a cat sat"
+	prefix := strings.Repeat("x", patternMaxLength+3000)
+	text := prefix + pattern
+
+	a := NewAutomaton()
+	if _, err := a.Add(NewPattern(pattern, pattern)); err != nil {
+		t.Fatalf("Add(%q): %v", pattern, err)
+	}
+	a.Finalize()
+
+	var found string
+	err := a.SearchStream(bytes.NewReader([]byte(text)), func(m Match, window string, windowStart int, _ string) bool {
+		start := m.StartOffset(0) - windowStart
+		end := m.Position() - windowStart
+		found = window[start:end]
+		return false
+	}, "", WithBufferSize(8000))
+	if err != nil {
+		t.Fatalf("SearchStream: %v", err)
+	}
+	if found != pattern {
+		t.Fatalf("window slice = %q, want %q", found, pattern)
+	}
+}